@@ -0,0 +1,107 @@
+package grouplogger
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync"
+
+	"cloud.google.com/go/errorreporting"
+	"google.golang.org/api/option"
+)
+
+// WithErrorReporting causes inner entries logged at logging.Error severity
+// or higher, through any GroupLogger built by this Client, to also be
+// reported to Cloud Error Reporting, tagged with serviceName and version.
+//
+// The Error Reporting client shares the Client's authentication and project,
+// and is constructed lazily, the first time it's needed.
+func WithErrorReporting(serviceName, version string) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.reportErrors = true
+		cfg.errService = serviceName
+		cfg.errVersion = version
+	}
+}
+
+// errorReportingClient is the subset of *errorreporting.Client's API that
+// errorReporter depends on, allowing it to be faked out in tests.
+type errorReportingClient interface {
+	Report(e errorreporting.Entry)
+	Close() error
+}
+
+// errorReporter lazily constructs the errorreporting.Client shared by every
+// GroupLogger a Client produces, and reports errors to it.
+type errorReporter struct {
+	ctx         context.Context
+	parent      string
+	serviceName string
+	version     string
+	apiOpts     []option.ClientOption
+
+	once   sync.Once
+	client errorReportingClient
+	err    error
+}
+
+// get returns the shared errorReportingClient, constructing it on first use
+// unless a test has already populated er.client. If construction fails, get
+// returns nil and the error is discarded; logging itself must not fail
+// because error reporting is unavailable.
+func (er *errorReporter) get() errorReportingClient {
+	er.once.Do(func() {
+		if er.client != nil {
+			return
+		}
+		client, err := errorreporting.NewClient(er.ctx, projectID(er.parent), errorreporting.Config{
+			ServiceName:    er.serviceName,
+			ServiceVersion: er.version,
+		}, er.apiOpts...)
+		er.err = err
+		if err == nil {
+			er.client = client
+		}
+	})
+	return er.client
+}
+
+// report sends payload, logged against req, to Cloud Error Reporting. If
+// payload is already an error, it's reported as-is; otherwise it's
+// stringified and a stack trace is captured at the call site.
+func (er *errorReporter) report(req *http.Request, payload interface{}) {
+	client := er.get()
+	if client == nil {
+		return
+	}
+	client.Report(errorEntry(req, payload))
+}
+
+// errorEntry builds the errorreporting.Entry for payload logged against req.
+// If payload is already an error, it's reported as-is; otherwise it's
+// stringified and a stack trace is captured at the call site.
+func errorEntry(req *http.Request, payload interface{}) errorreporting.Entry {
+	entry := errorreporting.Entry{Req: req}
+	if err, ok := payload.(error); ok {
+		entry.Error = err
+		return entry
+	}
+	entry.Error = fmt.Errorf("%v", payload)
+	buf := make([]byte, 16*1024)
+	entry.Stack = buf[:runtime.Stack(buf, false)]
+	return entry
+}
+
+// close flushes and closes the shared errorreporting.Client, if one was
+// constructed.
+func (er *errorReporter) close() error {
+	// Synchronize with any in-flight get(): er.client is only safe to read
+	// once the Once that guards its write has fired, whether or not this
+	// call is the one that fires it.
+	er.once.Do(func() {})
+	if er.client == nil {
+		return nil
+	}
+	return er.client.Close()
+}
@@ -0,0 +1,128 @@
+package grouplogger
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/errorreporting"
+	"cloud.google.com/go/logging"
+)
+
+// fakeErrorReportingClient is an errorReportingClient that records every
+// Entry passed to Report, for use in place of *errorreporting.Client in
+// tests.
+type fakeErrorReportingClient struct {
+	mu      sync.Mutex
+	entries []errorreporting.Entry
+	closed  bool
+}
+
+func (f *fakeErrorReportingClient) Report(e errorreporting.Entry) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.entries = append(f.entries, e)
+}
+
+func (f *fakeErrorReportingClient) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestErrorEntryWrapsNonErrorPayloadWithStack(t *testing.T) {
+	entry := errorEntry(nil, "boom")
+	if entry.Error == nil || entry.Error.Error() != "boom" {
+		t.Fatal(entry.Error)
+	}
+	if len(entry.Stack) == 0 {
+		t.Fatal("expected a captured stack trace for a non-error payload")
+	}
+}
+
+func TestErrorEntryPassesThroughErrorPayload(t *testing.T) {
+	want := errors.New("already an error")
+	entry := errorEntry(nil, want)
+	if entry.Error != want {
+		t.Fatal(entry.Error)
+	}
+	if len(entry.Stack) != 0 {
+		t.Fatal("expected no stack capture when the payload is already an error")
+	}
+}
+
+func TestErrorReporterReportUsesInjectedClient(t *testing.T) {
+	fake := &fakeErrorReportingClient{}
+	er := &errorReporter{client: fake}
+	er.once.Do(func() {}) // pre-fire so get() doesn't dial a real client
+
+	r, _ := http.NewRequest("GET", "https://www.vimeo.com", nil)
+	er.report(r, errors.New("kaboom"))
+
+	if len(fake.entries) != 1 || fake.entries[0].Req != r {
+		t.Fatal(fake.entries)
+	}
+}
+
+func TestErrorReporterCloseFlushesInjectedClient(t *testing.T) {
+	fake := &fakeErrorReportingClient{}
+	er := &errorReporter{client: fake}
+
+	if err := er.close(); err != nil {
+		t.Fatal(err)
+	}
+	if !fake.closed {
+		t.Fatal("expected close to flush the error reporting client")
+	}
+}
+
+func TestErrorReporterCloseNoopWithoutClient(t *testing.T) {
+	er := &errorReporter{}
+	if err := er.close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestErrorReporterCloseSynchronizesWithGet exercises close() racing a
+// concurrent first get(). Whichever goroutine's Once body runs first
+// determines whether er.client ends up set, but close() must never observe
+// a partially-constructed er.client; run with -race to catch a regression.
+func TestErrorReporterCloseSynchronizesWithGet(t *testing.T) {
+	fake := &fakeErrorReportingClient{}
+	er := &errorReporter{}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		er.once.Do(func() { er.client = fake })
+	}()
+	go func() {
+		defer wg.Done()
+		if err := er.close(); err != nil {
+			t.Error(err)
+		}
+	}()
+	wg.Wait()
+}
+
+func TestLogInnerEntryForwardsErrorsToReporter(t *testing.T) {
+	fake := &fakeErrorReportingClient{}
+	er := &errorReporter{client: fake}
+	er.once.Do(func() {})
+
+	r, _ := http.NewRequest("GET", "https://www.vimeo.com", nil)
+	gl := &GroupLogger{
+		Req:         r,
+		sampled:     true,
+		errReporter: er,
+		InnerLogger: &mockLogger{LogFunc: func(logging.Entry) {}},
+	}
+
+	gl.Info("below threshold")
+	gl.Error("over threshold")
+
+	if len(fake.entries) != 1 {
+		t.Fatal(fake.entries)
+	}
+}
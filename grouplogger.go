@@ -2,20 +2,20 @@
 // groups of log entries, similar to the default behavior in Google App Engine
 // Standard.
 //
-//		var r *http.Request
+//	var r *http.Request
 //
-//		ctx := context.Background()
-//		cli, err := NewClient(ctx, "logging-parent")
-//		if err != nil {
-//			// Handle "failed to generate Stackdriver client."
-//		}
+//	ctx := context.Background()
+//	cli, err := NewClient(ctx, "logging-parent")
+//	if err != nil {
+//		// Handle "failed to generate Stackdriver client."
+//	}
 //
-//		logger := cli.Logger(r, "app_identifier", logging.CommonLabels(WithHostname(nil)))
+//	logger := cli.Logger(r, "app_identifier", nil, logging.CommonLabels(WithHostname(nil)))
 //
-//		logger.Info("Info log entry body.")
-//		logger.Error("Error log entry body.")
+//	logger.Info("Info log entry body.")
+//	logger.Error("Error log entry body.")
 //
-//		logger.Close()
+//	logger.Close()
 package grouplogger
 
 import (
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 	"sync"
 
 	"cloud.google.com/go/compute/metadata"
@@ -38,41 +39,127 @@ const (
 	innerFormat = "%v-app"
 )
 
+// ClientOption configures a Client constructed by NewClient.
+type ClientOption func(*clientConfig)
+
+// clientConfig accumulates the ClientOptions passed to NewClient.
+type clientConfig struct {
+	apiOpts      []option.ClientOption
+	reportErrors bool
+	errService   string
+	errVersion   string
+	recover      bool
+	cacheHit     func(*http.Request) bool
+	loggerConfig *LoggerConfig
+}
+
+// WithAPIOptions forwards opts to the underlying Stackdriver clients, for
+// things like authentication and endpoint overrides.
+//
+// Options are documented here:
+// https://godoc.org/google.golang.org/api/option#ClientOption
+func WithAPIOptions(opts ...option.ClientOption) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.apiOpts = append(cfg.apiOpts, opts...)
+	}
+}
+
 // Client adds different Logger generation to Stackdriver's logging.Client.
 //
 // It can be reused across multiple requests to generate a Logger for each one
 // without repeating auth.
 type Client struct {
 	innerClient *logging.Client
+	errReporter *errorReporter
+	// project is the bare project ID, used to format the fully qualified
+	// Cloud Trace resource name set on each Logger's entries.
+	project string
+	// recover, cacheHit, and loggerConfig configure Middleware. See
+	// WithRecover, WithCacheHit, and WithLoggerConfig.
+	recover      bool
+	cacheHit     func(*http.Request) bool
+	loggerConfig *LoggerConfig
 }
 
 // NewClient generates a new Client associated with the provided parent.
 //
 // Options are documented here:
-// https://godoc.org/google.golang.org/api/option#ClientOption
-func NewClient(ctx context.Context, parent string, opts ...option.ClientOption) (*Client, error) {
-	client, err := logging.NewClient(ctx, parent, opts...)
+// https://godoc.org/google.golang.org/api/option#ClientOption. Pass them via
+// WithAPIOptions. Other ClientOptions, such as WithErrorReporting, configure
+// grouplogger itself.
+func NewClient(ctx context.Context, parent string, opts ...ClientOption) (*Client, error) {
+	var cfg clientConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	client, err := logging.NewClient(ctx, parent, cfg.apiOpts...)
 	if err != nil {
 		return &Client{}, err
 	}
-	return &Client{client}, nil
+	c := &Client{
+		innerClient:  client,
+		project:      projectID(parent),
+		recover:      cfg.recover,
+		cacheHit:     cfg.cacheHit,
+		loggerConfig: cfg.loggerConfig,
+	}
+	if cfg.reportErrors {
+		c.errReporter = &errorReporter{
+			ctx:         ctx,
+			parent:      parent,
+			serviceName: cfg.errService,
+			version:     cfg.errVersion,
+			apiOpts:     cfg.apiOpts,
+		}
+	}
+	return c, nil
 }
 
-// Close waits for all opened GroupLoggers to be flushed and closes the client.
+// Close waits for all opened GroupLoggers to be flushed and closes the
+// client, along with the Error Reporting client if WithErrorReporting was
+// used.
 func (client *Client) Close() error {
-	return client.innerClient.Close()
+	err := client.innerClient.Close()
+	if client.errReporter != nil {
+		if rerr := client.errReporter.close(); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
+	return err
 }
 
 // Logger constructs and returns a new GroupLogger object for a new group of log
 // entries corresponding to a request R.
 //
+// cfg configures sampling and filtering behavior for the returned
+// GroupLogger; a nil cfg keeps and writes every entry.
+//
 // Logger options (labels, resources, etc.) are documented here:
 // https://godoc.org/cloud.google.com/go/logging#LoggerOption
-func (client *Client) Logger(r *http.Request, name string, opts ...logging.LoggerOption) *GroupLogger {
+func (client *Client) Logger(r *http.Request, name string, cfg *LoggerConfig, opts ...logging.LoggerOption) *GroupLogger {
 	outerLogger := client.innerClient.Logger(fmt.Sprintf(outerFormat, name), opts...)
 	innerLogger := client.innerClient.Logger(fmt.Sprintf(innerFormat, name), opts...)
-	// Use trace from request if available; otherwise generate a group ID.
-	gl := &GroupLogger{r, getGroupID(r), outerLogger, innerLogger, nil}
+	// Use the trace context from the request if available; otherwise
+	// generate a group ID.
+	tc := getGroupID(r, defaultUUID)
+	gl := &GroupLogger{
+		Req:          r,
+		GroupID:      tc.TraceID,
+		OuterLogger:  outerLogger,
+		InnerLogger:  innerLogger,
+		Trace:        fmt.Sprintf("projects/%v/traces/%v", client.project, tc.TraceID),
+		SpanID:       tc.SpanID,
+		TraceSampled: tc.Sampled,
+		errReporter:  client.errReporter,
+		sampled:      true,
+	}
+	if cfg != nil {
+		gl.minSeverity = cfg.MinSeverity
+		if cfg.SampleRate > 0 && cfg.SampleRate < 1 {
+			gl.sampled = shouldSample(tc.TraceID, cfg.SampleRate)
+		}
+		gl.suppressOuter = matchesAnyPath(cfg.SuppressPaths, r)
+	}
 	return gl
 }
 
@@ -93,6 +180,12 @@ func (client *Client) SetOnError(f func(err error)) {
 	client.innerClient.OnError = f
 }
 
+// stackdriverLogger is the subset of *logging.Logger's API that GroupLogger
+// depends on, allowing it to be faked out in tests.
+type stackdriverLogger interface {
+	Log(e logging.Entry)
+}
+
 // GroupLogger wraps two Stackdriver Logger clients. The OuterLogger is used to
 // write the entries by which other entries are grouped: usually, these are
 // requests. The InnerLogger is used to write the grouped (enclosed) entries.
@@ -105,9 +198,30 @@ func (client *Client) SetOnError(f func(err error)) {
 type GroupLogger struct {
 	Req          *http.Request
 	GroupID      string
-	OuterLogger  *logging.Logger
-	InnerLogger  *logging.Logger
+	OuterLogger  stackdriverLogger
+	InnerLogger  stackdriverLogger
 	InnerEntries []logging.Entry
+
+	// Trace is the fully qualified Cloud Trace resource name
+	// ("projects/PROJECT/traces/TRACE_ID") set on every entry logged
+	// through this GroupLogger.
+	Trace string
+	// SpanID and TraceSampled are set on every entry logged through this
+	// GroupLogger from the request's trace context header, if one was
+	// present.
+	SpanID       string
+	TraceSampled bool
+
+	// errReporter forwards Error-severity-and-above inner entries to Cloud
+	// Error Reporting, if the Client that created this GroupLogger was
+	// built with WithErrorReporting. It is nil otherwise.
+	errReporter *errorReporter
+
+	// minSeverity, sampled, and suppressOuter implement the LoggerConfig
+	// passed to Client.Logger, if any. sampled defaults to true.
+	minSeverity   logging.Severity
+	sampled       bool
+	suppressOuter bool
 }
 
 // Close calls CloseWith without specifying statistics. It does not close the
@@ -124,11 +238,18 @@ func (gl *GroupLogger) Close() {
 //
 // If LogOuterEntry is not called, nothing from this group will appear in
 // the outer log.
+//
+// If the LoggerConfig passed to Client.Logger suppresses this group's path
+// and no inner entry at logging.Warning severity or higher was recorded,
+// CloseWith does nothing.
 func (gl *GroupLogger) CloseWith(stats *logging.HTTPRequest) {
+	maxSeverity := gl.getMaxSeverity()
+	if gl.suppressOuter && maxSeverity < logging.Warning {
+		return
+	}
 	stats.Request = gl.Req
 	entry := logging.Entry{
-		Trace:       gl.GroupID,
-		Severity:    gl.getMaxSeverity(),
+		Severity:    maxSeverity,
 		HTTPRequest: stats,
 	}
 	gl.LogOuterEntry(entry)
@@ -136,10 +257,29 @@ func (gl *GroupLogger) CloseWith(stats *logging.HTTPRequest) {
 
 // LogInnerEntry pushes an inner log entry for the group, decorated with the
 // GroupID.
+//
+// If entry.Severity is below the LoggerConfig's MinSeverity, or the group
+// wasn't sampled in and entry.Severity is below logging.Warning, entry is
+// dropped: it's neither written nor appended to InnerEntries.
+//
+// If entry.Severity is logging.Error or higher and the Client that created
+// this GroupLogger was built with WithErrorReporting, entry is also reported
+// to Cloud Error Reporting.
 func (gl *GroupLogger) LogInnerEntry(entry logging.Entry) {
-	entry.Trace = gl.GroupID
+	if entry.Severity < gl.minSeverity {
+		return
+	}
+	if !gl.sampled && entry.Severity < logging.Warning {
+		return
+	}
+	entry.Trace = gl.Trace
+	entry.SpanID = gl.SpanID
+	entry.TraceSampled = gl.TraceSampled
 	gl.InnerLogger.Log(entry)
 	gl.InnerEntries = append(gl.InnerEntries, entry)
+	if entry.Severity >= logging.Error && gl.errReporter != nil {
+		gl.errReporter.report(gl.Req, entry.Payload)
+	}
 }
 
 // LogOuterEntry pushes the top-level log entry for the group, decorated
@@ -148,7 +288,9 @@ func (gl *GroupLogger) LogInnerEntry(entry logging.Entry) {
 // For the group to be grouped in the GCP logging console, ENTRY must have
 // entry.HTTPRequest set.
 func (gl *GroupLogger) LogOuterEntry(entry logging.Entry) {
-	entry.Trace = gl.GroupID
+	entry.Trace = gl.Trace
+	entry.SpanID = gl.SpanID
+	entry.TraceSampled = gl.TraceSampled
 	gl.OuterLogger.Log(entry)
 }
 
@@ -252,22 +394,19 @@ func (gl *GroupLogger) getMaxSeverity() logging.Severity {
 	return max
 }
 
-// getGroupID selects an ID by which the group will be grouped in the Google
-// Cloud Logging console.
-//
-// If the `X-Cloud-Trace-Context` header is set in the request by GCP
-// middleware, then that trace ID is used.
-//
-// Otherwise, a pseudorandom UUID is used.
-func getGroupID(r *http.Request) string {
-	// If the trace header exists, use the trace.
-	if id := r.Header.Get("X-Cloud-Trace-Context"); id != "" {
-		return id
-	}
-	// Otherwise, generate a random group ID.
+// defaultUUID generates a pseudorandom UUID. It's the newUUID function
+// getGroupID falls back to outside of tests.
+func defaultUUID() string {
 	return uuid.New().String()
 }
 
+// projectID extracts the bare project ID from parent, which NewClient
+// accepts either as "my-project" or as the resource name
+// "projects/my-project".
+func projectID(parent string) string {
+	return strings.TrimPrefix(parent, "projects/")
+}
+
 var detectedHost struct {
 	hostname string
 	once     sync.Once
@@ -8,32 +8,78 @@ import (
 	"cloud.google.com/go/logging"
 )
 
-const fake_uuid = "fake_uuid"
+const fakeUUID = "fake_uuid"
 
 func mockUUIDFunc() string {
-	return fake_uuid
+	return fakeUUID
 }
 
-func TestGetGroupIDWithRequestWithHeader(t *testing.T) {
+// mockLogger is a stackdriverLogger that passes every entry logged through
+// it to LogFunc, for use in place of a *logging.Logger in tests.
+type mockLogger struct {
+	LogFunc func(e logging.Entry)
+}
+
+func (m *mockLogger) Log(e logging.Entry) {
+	m.LogFunc(e)
+}
+
+func TestGetGroupIDWithGCPHeader(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://www.vimeo.com", nil)
+	r.Header.Set("X-Cloud-Trace-Context", "105445aa7843bc8bf206b12000100000/1;o=1")
+	tc := getGroupID(r, mockUUIDFunc)
+	if tc.TraceID != "105445aa7843bc8bf206b12000100000" {
+		t.Fatal(tc.TraceID)
+	}
+	if tc.SpanID != "1" {
+		t.Fatal(tc.SpanID)
+	}
+	if !tc.Sampled {
+		t.Fatal(tc.Sampled)
+	}
+}
+
+func TestGetGroupIDWithGCPHeaderNoSpan(t *testing.T) {
 	r, _ := http.NewRequest("GET", "http://www.vimeo.com", nil)
-	r.Header.Set("X-Cloud-Trace-Context", "123")
-	id := getGroupID(r, mockUUIDFunc)
-	if id != "123" {
-		t.Fatal(id)
+	r.Header.Set("X-Cloud-Trace-Context", "105445aa7843bc8bf206b12000100000")
+	tc := getGroupID(r, mockUUIDFunc)
+	if tc.TraceID != "105445aa7843bc8bf206b12000100000" {
+		t.Fatal(tc.TraceID)
+	}
+	if tc.SpanID != "" {
+		t.Fatal(tc.SpanID)
+	}
+	if tc.Sampled {
+		t.Fatal(tc.Sampled)
+	}
+}
+
+func TestGetGroupIDWithW3CHeader(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://www.vimeo.com", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	tc := getGroupID(r, mockUUIDFunc)
+	if tc.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatal(tc.TraceID)
+	}
+	if tc.SpanID != "00f067aa0ba902b7" {
+		t.Fatal(tc.SpanID)
+	}
+	if !tc.Sampled {
+		t.Fatal(tc.Sampled)
 	}
 }
 
 func TestGetGroupIDWithRequestWithoutHeader(t *testing.T) {
-	id := getGroupID(&http.Request{}, mockUUIDFunc)
-	if id != fake_uuid {
-		t.Fatal(id)
+	tc := getGroupID(&http.Request{}, mockUUIDFunc)
+	if tc.TraceID != fakeUUID {
+		t.Fatal(tc.TraceID)
 	}
 }
 
 func TestGetGroupIDWithoutRequest(t *testing.T) {
-	id := getGroupID(nil, mockUUIDFunc)
-	if id != fake_uuid {
-		t.Fatal(id)
+	tc := getGroupID(nil, mockUUIDFunc)
+	if tc.TraceID != fakeUUID {
+		t.Fatal(tc.TraceID)
 	}
 }
 
@@ -43,8 +89,8 @@ func TestCloseWith(t *testing.T) {
 	r, _ := http.NewRequest("GET", "https://www.vimeo.com", nil)
 
 	gl := GroupLogger{
-		Req:     r,
-		GroupID: "fake_GroupID",
+		Req:   r,
+		Trace: "projects/fake_project/traces/fake_GroupID",
 		OuterLogger: &mockLogger{
 			LogFunc: func(e logging.Entry) {
 				outerEntry = e
@@ -73,6 +119,10 @@ func TestCloseWith(t *testing.T) {
 		t.Fatal(outerEntry.Severity.String())
 	}
 
+	if outerEntry.Trace != "projects/fake_project/traces/fake_GroupID" {
+		t.Fatal(outerEntry.Trace)
+	}
+
 	if outerEntry.HTTPRequest.Latency != 1*time.Second {
 		t.Fatal(outerEntry.HTTPRequest.Latency)
 	}
@@ -81,3 +131,72 @@ func TestCloseWith(t *testing.T) {
 		t.Fatal(outerEntry.HTTPRequest.Request.URL.String())
 	}
 }
+
+func TestLogInnerEntryDropsBelowMinSeverity(t *testing.T) {
+	var logged []logging.Entry
+
+	gl := GroupLogger{
+		minSeverity: logging.Warning,
+		sampled:     true,
+		InnerLogger: &mockLogger{
+			LogFunc: func(e logging.Entry) { logged = append(logged, e) },
+		},
+	}
+
+	gl.Info("dropped")
+	gl.Warning("kept")
+
+	if len(gl.InnerEntries) != 1 || gl.InnerEntries[0].Payload != "kept" {
+		t.Fatal(gl.InnerEntries)
+	}
+	if len(logged) != 1 || logged[0].Payload != "kept" {
+		t.Fatal(logged)
+	}
+}
+
+func TestLogInnerEntryUnsampledKeepsWarnings(t *testing.T) {
+	var logged []logging.Entry
+
+	gl := GroupLogger{
+		sampled: false,
+		InnerLogger: &mockLogger{
+			LogFunc: func(e logging.Entry) { logged = append(logged, e) },
+		},
+	}
+
+	gl.Info("dropped")
+	gl.Warning("kept")
+
+	if len(gl.InnerEntries) != 1 || gl.InnerEntries[0].Payload != "kept" {
+		t.Fatal(gl.InnerEntries)
+	}
+	if len(logged) != 1 {
+		t.Fatal(logged)
+	}
+}
+
+func TestCloseWithSuppressesQuietPath(t *testing.T) {
+	var logged bool
+
+	r, _ := http.NewRequest("GET", "https://www.vimeo.com/healthz", nil)
+	gl := GroupLogger{
+		Req:           r,
+		suppressOuter: true,
+		OuterLogger: &mockLogger{
+			LogFunc: func(e logging.Entry) { logged = true },
+		},
+	}
+
+	gl.Close()
+
+	if logged {
+		t.Fatal("expected the outer entry to be suppressed")
+	}
+
+	gl.InnerEntries = []logging.Entry{{Severity: logging.Warning}}
+	gl.Close()
+
+	if !logged {
+		t.Fatal("expected a Warning+ inner entry to un-suppress the outer entry")
+	}
+}
@@ -0,0 +1,133 @@
+package grouplogger
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"cloud.google.com/go/logging"
+)
+
+// WithRecover causes Middleware's handler to catch panics from the wrapped
+// handler, log them at Emergency severity with a stack trace, close the
+// request's GroupLogger as usual, and then re-panic so that other
+// middleware (or net/http's own recovery logging) still sees it.
+func WithRecover() ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.recover = true
+	}
+}
+
+// WithCacheHit supplies a hook Middleware calls after each request to
+// populate logging.HTTPRequest.CacheHit on the outer entry.
+func WithCacheHit(f func(*http.Request) bool) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.cacheHit = f
+	}
+}
+
+// contextKey is an unexported type for the context key Middleware uses, so
+// it can't collide with keys set by other packages.
+type contextKey struct{}
+
+// groupLoggerKey is the context key under which Middleware stores a
+// request's GroupLogger.
+var groupLoggerKey contextKey
+
+// FromContext returns the GroupLogger that Middleware stored in ctx, or nil
+// if ctx didn't come from a request Middleware handled.
+func FromContext(ctx context.Context) *GroupLogger {
+	gl, _ := ctx.Value(groupLoggerKey).(*GroupLogger)
+	return gl
+}
+
+// Middleware returns net/http middleware that, for every request, builds a
+// GroupLogger named name (available to handlers via FromContext) and closes
+// it once the handler returns, populating the outer entry's
+// logging.HTTPRequest with the response status, size, latency, and remote
+// IP.
+//
+// If the Client was built with WithRecover, a panic from the wrapped
+// handler is logged at Emergency severity with a stack trace before the
+// GroupLogger is closed and the panic is re-raised.
+func (client *Client) Middleware(name string, opts ...logging.LoggerOption) func(http.Handler) http.Handler {
+	return client.middleware(client.Logger, name, opts...)
+}
+
+// middleware does the work of Middleware, taking newLogger as a parameter
+// so tests can substitute a GroupLogger constructor that doesn't need a
+// real logging.Client.
+func (client *Client) middleware(newLogger func(*http.Request, string, *LoggerConfig, ...logging.LoggerOption) *GroupLogger, name string, opts ...logging.LoggerOption) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gl := newLogger(r, name, client.loggerConfig, opts...)
+			r = r.WithContext(context.WithValue(r.Context(), groupLoggerKey, gl))
+			rw := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			start := time.Now()
+
+			if client.recover {
+				defer func() {
+					if p := recover(); p != nil {
+						gl.Emergency(fmt.Sprintf("panic: %v\n%s", p, debug.Stack()))
+						client.closeRequest(gl, rw, r, start)
+						panic(p)
+					}
+				}()
+			}
+
+			next.ServeHTTP(rw, r)
+			client.closeRequest(gl, rw, r, start)
+		})
+	}
+}
+
+// closeRequest builds the outer logging.HTTPRequest for a request Middleware
+// handled and closes gl with it.
+func (client *Client) closeRequest(gl *GroupLogger, rw *statusRecorder, r *http.Request, start time.Time) {
+	stats := &logging.HTTPRequest{
+		Status:       rw.status,
+		ResponseSize: rw.bytes,
+		Latency:      time.Since(start),
+		RemoteIP:     remoteIP(r),
+	}
+	if client.cacheHit != nil {
+		stats.CacheHit = client.cacheHit(r)
+	}
+	gl.CloseWith(stats)
+}
+
+// remoteIP extracts the client IP from r.RemoteAddr, stripping the port if
+// one is present.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// statusRecorder wraps an http.ResponseWriter to capture the status code
+// and number of bytes written, for populating an outer request entry.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// WriteHeader records status before delegating to the wrapped
+// ResponseWriter.
+func (rw *statusRecorder) WriteHeader(status int) {
+	rw.status = status
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the number of bytes written before delegating to the
+// wrapped ResponseWriter.
+func (rw *statusRecorder) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += int64(n)
+	return n, err
+}
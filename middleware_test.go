@@ -0,0 +1,135 @@
+package grouplogger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cloud.google.com/go/logging"
+)
+
+// newLoggerForTest returns a GroupLogger constructor, matching the shape
+// Client.Middleware passes to client.middleware, that always returns gl
+// instead of calling through to a real logging.Client.
+func newLoggerForTest(gl *GroupLogger) func(*http.Request, string, *LoggerConfig, ...logging.LoggerOption) *GroupLogger {
+	return func(r *http.Request, _ string, _ *LoggerConfig, _ ...logging.LoggerOption) *GroupLogger {
+		gl.Req = r
+		return gl
+	}
+}
+
+func TestMiddlewareCapturesStatusAndInjectsGroupLogger(t *testing.T) {
+	var outerEntry logging.Entry
+	gl := &GroupLogger{
+		sampled:     true,
+		OuterLogger: &mockLogger{LogFunc: func(e logging.Entry) { outerEntry = e }},
+		InnerLogger: &mockLogger{LogFunc: func(logging.Entry) {}},
+	}
+
+	var fromContext *GroupLogger
+	client := &Client{}
+	mw := client.middleware(newLoggerForTest(gl), "app")
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fromContext = FromContext(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+		_, _ = w.Write([]byte("hi"))
+	}))
+
+	r := httptest.NewRequest("GET", "/widgets", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if fromContext != gl {
+		t.Fatal("expected the handler to see the GroupLogger via FromContext")
+	}
+	if outerEntry.HTTPRequest.Status != http.StatusTeapot {
+		t.Fatal(outerEntry.HTTPRequest.Status)
+	}
+	if outerEntry.HTTPRequest.ResponseSize != int64(len("hi")) {
+		t.Fatal(outerEntry.HTTPRequest.ResponseSize)
+	}
+}
+
+func TestMiddlewareRecoverLogsAndRepanics(t *testing.T) {
+	var innerEntries []logging.Entry
+	var outerEntry logging.Entry
+	gl := &GroupLogger{
+		sampled:     true,
+		OuterLogger: &mockLogger{LogFunc: func(e logging.Entry) { outerEntry = e }},
+		InnerLogger: &mockLogger{LogFunc: func(e logging.Entry) { innerEntries = append(innerEntries, e) }},
+	}
+
+	client := &Client{recover: true}
+	mw := client.middleware(newLoggerForTest(gl), "app")
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	r := httptest.NewRequest("GET", "/widgets", nil)
+	w := httptest.NewRecorder()
+
+	defer func() {
+		p := recover()
+		if p == nil {
+			t.Fatal("expected the panic to be re-raised")
+		}
+		if len(innerEntries) != 1 || innerEntries[0].Severity != logging.Emergency {
+			t.Fatal(innerEntries)
+		}
+		if outerEntry.Severity != logging.Emergency {
+			t.Fatal("expected CloseWith to run before the re-panic", outerEntry)
+		}
+	}()
+	handler.ServeHTTP(w, r)
+}
+
+func TestMiddlewareCacheHit(t *testing.T) {
+	var outerEntry logging.Entry
+	gl := &GroupLogger{
+		sampled:     true,
+		OuterLogger: &mockLogger{LogFunc: func(e logging.Entry) { outerEntry = e }},
+		InnerLogger: &mockLogger{LogFunc: func(logging.Entry) {}},
+	}
+
+	client := &Client{cacheHit: func(*http.Request) bool { return true }}
+	mw := client.middleware(newLoggerForTest(gl), "app")
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	r := httptest.NewRequest("GET", "/widgets", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if !outerEntry.HTTPRequest.CacheHit {
+		t.Fatal("expected CacheHit to be populated from the cacheHit hook")
+	}
+}
+
+func TestFromContextWithoutMiddleware(t *testing.T) {
+	if gl := FromContext(httptest.NewRequest("GET", "/", nil).Context()); gl != nil {
+		t.Fatal(gl)
+	}
+}
+
+func TestStatusRecorderDefaultsToOK(t *testing.T) {
+	w := httptest.NewRecorder()
+	rw := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	n, err := rw.Write([]byte("hello"))
+	if err != nil || n != 5 {
+		t.Fatal(n, err)
+	}
+	if rw.status != http.StatusOK || rw.bytes != 5 {
+		t.Fatal(rw.status, rw.bytes)
+	}
+}
+
+func TestRemoteIPStripsPort(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "203.0.113.5:54321"
+	if ip := remoteIP(r); ip != "203.0.113.5" {
+		t.Fatal(ip)
+	}
+
+	r.RemoteAddr = "not-a-host-port"
+	if ip := remoteIP(r); ip != "not-a-host-port" {
+		t.Fatal(ip)
+	}
+}
@@ -0,0 +1,65 @@
+package grouplogger
+
+import (
+	"hash/fnv"
+	"math"
+	"net/http"
+	"path"
+
+	"cloud.google.com/go/logging"
+)
+
+// LoggerConfig configures optional sampling and filtering behavior for a
+// GroupLogger, beyond the labels/resource options already handled by
+// logging.LoggerOption.
+type LoggerConfig struct {
+	// MinSeverity, if set above its zero value (logging.Default), drops
+	// inner entries logged below it: they're neither written to Cloud
+	// Logging nor recorded in InnerEntries.
+	MinSeverity logging.Severity
+
+	// SampleRate, in (0, 1), is the probability that a group's entries
+	// below logging.Warning are kept; entries at Warning or above are
+	// always kept, so sampling doesn't hide failing requests. The
+	// decision is made once per group, keyed by a hash of its trace ID,
+	// so every entry in the same trace is kept or dropped together.
+	//
+	// Zero (the default) and any value >= 1 mean "always keep".
+	SampleRate float64
+
+	// SuppressPaths lists path.Match glob patterns (e.g. "/healthz",
+	// "/metrics/*"). A request whose URL path matches one of them has its
+	// outer entry suppressed by CloseWith, unless an inner entry at
+	// logging.Warning severity or higher was recorded for it.
+	SuppressPaths []string
+}
+
+// WithLoggerConfig causes GroupLoggers that Client.Middleware builds to
+// apply cfg's sampling and filtering rules. Callers that build GroupLoggers
+// directly via Client.Logger pass the same *LoggerConfig there instead.
+func WithLoggerConfig(cfg *LoggerConfig) ClientOption {
+	return func(c *clientConfig) {
+		c.loggerConfig = cfg
+	}
+}
+
+// shouldSample reports whether a group keyed by traceID should be sampled
+// in at rate, which must be in (0, 1).
+func shouldSample(traceID string, rate float64) bool {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(traceID))
+	return float64(h.Sum64()) < rate*float64(math.MaxUint64)
+}
+
+// matchesAnyPath reports whether r's URL path matches any of patterns.
+func matchesAnyPath(patterns []string, r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, r.URL.Path); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
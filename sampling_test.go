@@ -0,0 +1,109 @@
+package grouplogger
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cloud.google.com/go/logging"
+)
+
+func TestShouldSample(t *testing.T) {
+	// A rate of 1 keeps everything, 0 keeps nothing, and the decision for a
+	// given trace ID is stable across repeated calls.
+	if !shouldSample("trace-a", 1) {
+		t.Fatal("rate 1 should always sample in")
+	}
+	if shouldSample("trace-a", 0) {
+		t.Fatal("rate 0 should never sample in")
+	}
+
+	first := shouldSample("trace-a", 0.5)
+	for i := 0; i < 10; i++ {
+		if shouldSample("trace-a", 0.5) != first {
+			t.Fatal("shouldSample must be deterministic for a given trace ID and rate")
+		}
+	}
+}
+
+func TestShouldSampleDistributesAcrossTraceIDs(t *testing.T) {
+	sampledIn := 0
+	const n = 2000
+	for i := 0; i < n; i++ {
+		if shouldSample(fakeTraceID(i), 0.25) {
+			sampledIn++
+		}
+	}
+	// fnv isn't perfectly uniform, so allow a generous margin around the
+	// expected 25%.
+	if sampledIn < n/8 || sampledIn > n*3/8 {
+		t.Fatalf("expected roughly 25%% of %d trace IDs to sample in, got %d", n, sampledIn)
+	}
+}
+
+func fakeTraceID(i int) string {
+	return fmt.Sprintf("trace-%d", i)
+}
+
+func TestMatchesAnyPath(t *testing.T) {
+	patterns := []string{"/healthz", "/metrics/*"}
+
+	cases := []struct {
+		path string
+		want bool
+	}{
+		{"/healthz", true},
+		{"/metrics/cpu", true},
+		{"/metrics", false},
+		{"/widgets", false},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", c.path, nil)
+		if got := matchesAnyPath(patterns, r); got != c.want {
+			t.Errorf("matchesAnyPath(%v, %q) = %v, want %v", patterns, c.path, got, c.want)
+		}
+	}
+}
+
+func TestMatchesAnyPathNilRequest(t *testing.T) {
+	if matchesAnyPath([]string{"/healthz"}, nil) {
+		t.Fatal("a nil request should never match")
+	}
+}
+
+// TestClientMiddlewareAppliesLoggerConfig is a regression test for
+// 24c7c3c/43cc7c2: the first cut of LoggerConfig support constructed
+// GroupLoggers via client.Logger(r, name, nil, ...), so a LoggerConfig
+// passed via WithLoggerConfig never reached Middleware-based callers. It
+// drives client.middleware, the body of Client.Middleware, with a newLogger
+// that stands in for Client.Logger and checks it receives the Client's
+// LoggerConfig.
+func TestClientMiddlewareAppliesLoggerConfig(t *testing.T) {
+	client := &Client{
+		loggerConfig: &LoggerConfig{SuppressPaths: []string{"/healthz"}},
+	}
+
+	var gl *GroupLogger
+	newLogger := func(r *http.Request, name string, cfg *LoggerConfig, opts ...logging.LoggerOption) *GroupLogger {
+		if cfg != client.loggerConfig {
+			t.Fatal("expected the Client's loggerConfig to be passed through to newLogger")
+		}
+		gl = &GroupLogger{
+			Req:           r,
+			suppressOuter: matchesAnyPath(cfg.SuppressPaths, r),
+			OuterLogger:   &mockLogger{LogFunc: func(logging.Entry) { t.Fatal("outer entry should have been suppressed") }},
+			InnerLogger:   &mockLogger{LogFunc: func(logging.Entry) {}},
+			sampled:       true,
+		}
+		return gl
+	}
+
+	mw := client.middleware(newLogger, "app")
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+
+	if gl == nil || !gl.suppressOuter {
+		t.Fatal("expected the LoggerConfig's SuppressPaths to reach the GroupLogger")
+	}
+}
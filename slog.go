@@ -0,0 +1,235 @@
+package grouplogger
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"time"
+
+	"cloud.google.com/go/logging"
+	logpb "google.golang.org/genproto/googleapis/logging/v2"
+)
+
+// defaultExtendedLevelStep is the ExtendedLevelStep used if
+// WithExtendedLevelStep isn't passed to NewSlogHandler.
+const defaultExtendedLevelStep = 4
+
+// SlogHandlerOption configures a handler constructed by NewSlogHandler.
+type SlogHandlerOption func(*slogHandler)
+
+// WithExtendedLevelStep sets the size, in slog.Level units, of each step
+// above slog.LevelError that the handler maps to logging.Critical,
+// logging.Alert, and logging.Emergency in turn. It mirrors the 4-unit
+// spacing slog uses between its own built-in levels, so a caller that
+// defines custom levels a step or two above LevelError (as slog's
+// documentation recommends) lands on the right Stackdriver severity.
+//
+// The default, used if this option isn't passed, is 4.
+func WithExtendedLevelStep(step int) SlogHandlerOption {
+	return func(h *slogHandler) {
+		h.extendedLevelStep = step
+	}
+}
+
+// NewSlogHandler returns an slog.Handler that writes records as inner
+// entries of gl, the GroupLogger they should be grouped under. If opts is
+// nil, the zero value of slog.HandlerOptions is used. opts.AddSource and
+// opts.ReplaceAttr are honored, as documented on slog.HandlerOptions;
+// opts.Level gates Enabled as usual.
+//
+// This lets codebases that have standardized on log/slog keep using it while
+// still getting grouplogger's per-request grouping: pass the handler to
+// slog.New and use the resulting *slog.Logger as usual.
+func NewSlogHandler(gl *GroupLogger, opts *slog.HandlerOptions, hOpts ...SlogHandlerOption) slog.Handler {
+	h := &slogHandler{gl: gl, extendedLevelStep: defaultExtendedLevelStep}
+	if opts != nil {
+		h.opts = *opts
+	}
+	for _, o := range hOpts {
+		o(h)
+	}
+	return h
+}
+
+// SlogHandler is a convenience wrapper around NewSlogHandler for callers
+// that already hold a Client.
+func (client *Client) SlogHandler(gl *GroupLogger, opts *slog.HandlerOptions, hOpts ...SlogHandlerOption) slog.Handler {
+	return NewSlogHandler(gl, opts, hOpts...)
+}
+
+// slogHandler implements slog.Handler by translating records into inner
+// entries of a GroupLogger.
+type slogHandler struct {
+	gl   *GroupLogger
+	opts slog.HandlerOptions
+
+	// extendedLevelStep is the step size slogLevelToSeverity uses above
+	// slog.LevelError. See WithExtendedLevelStep.
+	extendedLevelStep int
+
+	groups []string
+	attrs  []groupedAttr
+}
+
+// groupedAttr is an attribute bound via WithAttrs, tagged with the group
+// path that was open when it was added.
+type groupedAttr struct {
+	groups []string
+	attr   slog.Attr
+}
+
+// Enabled reports whether level is at or above the handler's configured
+// minimum, defaulting to slog.LevelInfo as slog.HandlerOptions documents.
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	min := slog.LevelInfo
+	if h.opts.Level != nil {
+		min = h.opts.Level.Level()
+	}
+	return level >= min
+}
+
+// Handle logs record as an inner entry of the handler's GroupLogger, with
+// record.Message, if non-empty, stored in Payload under slog.MessageKey.
+//
+// If opts.ReplaceAttr was set, it's applied to every attribute (including
+// the record's Time and Message, but not its Level, which always
+// determines Severity) before it's written to Payload, exactly as
+// documented on slog.HandlerOptions. If opts.AddSource was not set,
+// Entry.SourceLocation is left nil, regardless of whether record carries a
+// PC.
+func (h *slogHandler) Handle(_ context.Context, record slog.Record) error {
+	payload := make(map[string]interface{})
+	if msg := record.Message; msg != "" {
+		h.setSlogAttr(payload, nil, slog.String(slog.MessageKey, msg))
+	}
+	for _, ga := range h.attrs {
+		h.setSlogAttr(payload, ga.groups, ga.attr)
+	}
+	record.Attrs(func(a slog.Attr) bool {
+		h.setSlogAttr(payload, h.groups, a)
+		return true
+	})
+
+	ts := record.Time
+	if h.opts.ReplaceAttr != nil && !ts.IsZero() {
+		a := h.opts.ReplaceAttr(nil, slog.Time(slog.TimeKey, ts))
+		ts = time.Time{}
+		if a.Key != "" {
+			ts = a.Value.Resolve().Time()
+		}
+	}
+
+	entry := logging.Entry{
+		Timestamp: ts,
+		Severity:  slogLevelToSeverity(record.Level, h.extendedLevelStep),
+		Payload:   payload,
+	}
+	if h.opts.AddSource && record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		if frame.File != "" || frame.Function != "" {
+			entry.SourceLocation = &logpb.LogEntrySourceLocation{
+				File:     frame.File,
+				Line:     int64(frame.Line),
+				Function: frame.Function,
+			}
+		}
+	}
+	h.gl.LogInnerEntry(entry)
+	return nil
+}
+
+// WithAttrs returns a copy of the handler that adds attrs to every
+// subsequent record, nested under any group opened by a prior WithGroup.
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := h.clone()
+	for _, a := range attrs {
+		clone.attrs = append(clone.attrs, groupedAttr{groups: h.groups, attr: a})
+	}
+	return clone
+}
+
+// WithGroup returns a copy of the handler that nests subsequent attributes
+// and record attrs under name in the entry's Payload.
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	clone := h.clone()
+	clone.groups = append(clone.groups, name)
+	return clone
+}
+
+func (h *slogHandler) clone() *slogHandler {
+	return &slogHandler{
+		gl:                h.gl,
+		opts:              h.opts,
+		extendedLevelStep: h.extendedLevelStep,
+		groups:            append([]string(nil), h.groups...),
+		attrs:             append([]groupedAttr(nil), h.attrs...),
+	}
+}
+
+// setSlogAttr writes a into m, creating a nested map for each entry in
+// groups and for any group-valued attr along the way. If the handler's
+// opts.ReplaceAttr is set, it's called with the full group path for every
+// non-group attribute, and the attribute is dropped if ReplaceAttr returns
+// a zero Attr, matching slog.HandlerOptions's documented contract.
+func (h *slogHandler) setSlogAttr(m map[string]interface{}, groups []string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if a.Key == "" && v.Kind() != slog.KindGroup {
+		return
+	}
+	if v.Kind() == slog.KindGroup {
+		childGroups := groups
+		if a.Key != "" {
+			childGroups = append(append([]string(nil), groups...), a.Key)
+		}
+		for _, ga := range v.Group() {
+			h.setSlogAttr(m, childGroups, ga)
+		}
+		return
+	}
+	if h.opts.ReplaceAttr != nil {
+		a = h.opts.ReplaceAttr(groups, slog.Attr{Key: a.Key, Value: v})
+		if a.Key == "" {
+			return
+		}
+		v = a.Value.Resolve()
+	}
+	for _, g := range groups {
+		m = subMap(m, g)
+	}
+	m[a.Key] = v.Any()
+}
+
+// subMap returns the map[string]interface{} stored at m[key], creating and
+// inserting one if key is absent or holds something else.
+func subMap(m map[string]interface{}, key string) map[string]interface{} {
+	sub, ok := m[key].(map[string]interface{})
+	if !ok {
+		sub = make(map[string]interface{})
+		m[key] = sub
+	}
+	return sub
+}
+
+// slogLevelToSeverity maps an slog.Level to the logging.Severity the
+// GroupLogger helper methods (Debug, Info, ... Emergency) use for the
+// equivalent concept, using extendedLevelStep (see WithExtendedLevelStep)
+// for levels above slog.LevelError.
+func slogLevelToSeverity(level slog.Level, extendedLevelStep int) logging.Severity {
+	step := slog.Level(extendedLevelStep)
+	switch {
+	case level < slog.LevelInfo:
+		return logging.Debug
+	case level < slog.LevelWarn:
+		return logging.Info
+	case level < slog.LevelError:
+		return logging.Warning
+	case level < slog.LevelError+step:
+		return logging.Error
+	case level < slog.LevelError+2*step:
+		return logging.Critical
+	case level < slog.LevelError+3*step:
+		return logging.Alert
+	default:
+		return logging.Emergency
+	}
+}
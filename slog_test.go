@@ -0,0 +1,154 @@
+package grouplogger
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging"
+)
+
+var fixedTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func TestSlogHandlerCollectsAttrsAndGroups(t *testing.T) {
+	gl := &GroupLogger{sampled: true}
+	var entry logging.Entry
+	gl.InnerLogger = &mockLogger{LogFunc: func(e logging.Entry) { entry = e }}
+	h := NewSlogHandler(gl, nil).WithAttrs([]slog.Attr{slog.String("bound", "1")}).WithGroup("g")
+
+	rec := slog.NewRecord(fixedTime, slog.LevelInfo, "msg", 0)
+	rec.AddAttrs(slog.Int("count", 2))
+	if err := h.Handle(context.Background(), rec); err != nil {
+		t.Fatal(err)
+	}
+
+	payload, ok := entry.Payload.(map[string]interface{})
+	if !ok {
+		t.Fatalf("payload is %T, not a map", entry.Payload)
+	}
+	if payload["bound"] != "1" {
+		t.Fatal(payload)
+	}
+	group, ok := payload["g"].(map[string]interface{})
+	if !ok || group["count"] != int64(2) {
+		t.Fatal(payload)
+	}
+	if payload[slog.MessageKey] != "msg" {
+		t.Fatal(payload)
+	}
+}
+
+func TestSlogHandlerOmitsEmptyMessage(t *testing.T) {
+	gl := &GroupLogger{sampled: true}
+	var entry logging.Entry
+	gl.InnerLogger = &mockLogger{LogFunc: func(e logging.Entry) { entry = e }}
+	h := NewSlogHandler(gl, nil)
+
+	if err := h.Handle(context.Background(), slog.NewRecord(fixedTime, slog.LevelInfo, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	payload := entry.Payload.(map[string]interface{})
+	if _, ok := payload[slog.MessageKey]; ok {
+		t.Fatal("expected no msg key for an empty message", payload)
+	}
+}
+
+func TestSlogHandlerSeverity(t *testing.T) {
+	gl := &GroupLogger{sampled: true}
+	var entry logging.Entry
+	gl.InnerLogger = &mockLogger{LogFunc: func(e logging.Entry) { entry = e }}
+	h := NewSlogHandler(gl, nil)
+
+	if err := h.Handle(context.Background(), slog.NewRecord(fixedTime, slog.LevelWarn, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Severity != logging.Warning {
+		t.Fatal(entry.Severity)
+	}
+}
+
+func TestSlogHandlerExtendedLevelStep(t *testing.T) {
+	gl := &GroupLogger{sampled: true}
+	var entry logging.Entry
+	gl.InnerLogger = &mockLogger{LogFunc: func(e logging.Entry) { entry = e }}
+	h := NewSlogHandler(gl, nil, WithExtendedLevelStep(2))
+
+	if err := h.Handle(context.Background(), slog.NewRecord(fixedTime, slog.LevelError+2, "", 0)); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Severity != logging.Critical {
+		t.Fatal(entry.Severity)
+	}
+}
+
+func TestSlogHandlerAddSourceGatesSourceLocation(t *testing.T) {
+	pc, _, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatal("runtime.Caller(0) failed")
+	}
+
+	gl := &GroupLogger{sampled: true}
+	var withoutSource logging.Entry
+	gl.InnerLogger = &mockLogger{LogFunc: func(e logging.Entry) { withoutSource = e }}
+	h := NewSlogHandler(gl, nil)
+	if err := h.Handle(context.Background(), slog.NewRecord(fixedTime, slog.LevelInfo, "", pc)); err != nil {
+		t.Fatal(err)
+	}
+	if withoutSource.SourceLocation != nil {
+		t.Fatal("expected no SourceLocation without AddSource", withoutSource.SourceLocation)
+	}
+
+	var withSource logging.Entry
+	gl.InnerLogger = &mockLogger{LogFunc: func(e logging.Entry) { withSource = e }}
+	h = NewSlogHandler(gl, &slog.HandlerOptions{AddSource: true})
+	if err := h.Handle(context.Background(), slog.NewRecord(fixedTime, slog.LevelInfo, "", pc)); err != nil {
+		t.Fatal(err)
+	}
+	if withSource.SourceLocation == nil {
+		t.Fatal("expected a SourceLocation with AddSource")
+	}
+}
+
+func TestSlogHandlerReplaceAttr(t *testing.T) {
+	gl := &GroupLogger{sampled: true}
+	var entry logging.Entry
+	gl.InnerLogger = &mockLogger{LogFunc: func(e logging.Entry) { entry = e }}
+
+	opts := &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == "secret" {
+				return slog.Attr{}
+			}
+			if a.Key == "name" {
+				return slog.String("name", "[redacted]")
+			}
+			return a
+		},
+	}
+	h := NewSlogHandler(gl, opts)
+	rec := slog.NewRecord(fixedTime, slog.LevelInfo, "", 0)
+	rec.AddAttrs(slog.String("secret", "hunter2"), slog.String("name", "alice"))
+	if err := h.Handle(context.Background(), rec); err != nil {
+		t.Fatal(err)
+	}
+
+	payload := entry.Payload.(map[string]interface{})
+	if _, ok := payload["secret"]; ok {
+		t.Fatal("secret attr should have been dropped by ReplaceAttr")
+	}
+	if payload["name"] != "[redacted]" {
+		t.Fatal(payload)
+	}
+}
+
+func TestSlogHandlerEnabled(t *testing.T) {
+	h := NewSlogHandler(&GroupLogger{}, &slog.HandlerOptions{Level: slog.LevelWarn})
+	if h.Enabled(context.Background(), slog.LevelInfo) {
+		t.Fatal("LevelInfo should not be enabled when the floor is LevelWarn")
+	}
+	if !h.Enabled(context.Background(), slog.LevelWarn) {
+		t.Fatal("LevelWarn should be enabled when the floor is LevelWarn")
+	}
+}
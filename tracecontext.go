@@ -0,0 +1,97 @@
+package grouplogger
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// gcpTraceHeader is the header GCP's HTTP(S) load balancer and App
+	// Engine front end set on incoming requests.
+	gcpTraceHeader = "X-Cloud-Trace-Context"
+	// w3cTraceHeader is the W3C Trace Context header.
+	// See https://www.w3.org/TR/trace-context/#traceparent-header.
+	w3cTraceHeader = "traceparent"
+)
+
+// traceContext identifies the trace and span a request belongs to, as
+// carried by either GCP's or the W3C's trace propagation headers.
+type traceContext struct {
+	TraceID string
+	SpanID  string
+	Sampled bool
+}
+
+// parseGCPTraceContext parses the `X-Cloud-Trace-Context` header, formatted
+// as `TRACE_ID/SPAN_ID;o=OPTIONS`, where the `/SPAN_ID` and `;o=OPTIONS`
+// suffixes are both optional. SPAN_ID is decimal, as GCP's tooling emits it,
+// and is converted to hex to match logging.Entry.SpanID's expected format.
+func parseGCPTraceContext(header string) (traceContext, bool) {
+	if header == "" {
+		return traceContext{}, false
+	}
+	traceID := header
+	var spanDecimal, options string
+	if i := strings.IndexByte(header, '/'); i >= 0 {
+		traceID = header[:i]
+		rest := header[i+1:]
+		if j := strings.IndexByte(rest, ';'); j >= 0 {
+			spanDecimal, options = rest[:j], rest[j+1:]
+		} else {
+			spanDecimal = rest
+		}
+	}
+	if traceID == "" {
+		return traceContext{}, false
+	}
+	tc := traceContext{TraceID: traceID}
+	if spanDecimal != "" {
+		if span, err := strconv.ParseUint(spanDecimal, 10, 64); err == nil {
+			tc.SpanID = strconv.FormatUint(span, 16)
+		}
+	}
+	if opt, ok := strings.CutPrefix(options, "o="); ok {
+		tc.Sampled = opt == "1"
+	}
+	return tc, true
+}
+
+// parseW3CTraceContext parses the W3C `traceparent` header, formatted as
+// `00-<32 hex trace ID>-<16 hex span ID>-<2 hex flags>`. The low bit of the
+// flags byte is the "sampled" flag.
+func parseW3CTraceContext(header string) (traceContext, bool) {
+	parts := strings.Split(header, "-")
+	if len(parts) != 4 || parts[0] != "00" ||
+		len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return traceContext{}, false
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return traceContext{}, false
+	}
+	return traceContext{
+		TraceID: parts[1],
+		SpanID:  parts[2],
+		Sampled: flags&1 == 1,
+	}, true
+}
+
+// getGroupID selects the traceContext by which a GroupLogger's entries will
+// be grouped in the Google Cloud Logging and Trace consoles.
+//
+// If the `X-Cloud-Trace-Context` header is set in the request, typically by
+// GCP's HTTP(S) load balancer, it's used. Otherwise, if the W3C `traceparent`
+// header is set, it's used instead. If neither is present (or r is nil),
+// newUUID is used to generate a TraceID and the SpanID is left empty.
+func getGroupID(r *http.Request, newUUID func() string) traceContext {
+	if r != nil {
+		if tc, ok := parseGCPTraceContext(r.Header.Get(gcpTraceHeader)); ok {
+			return tc
+		}
+		if tc, ok := parseW3CTraceContext(r.Header.Get(w3cTraceHeader)); ok {
+			return tc
+		}
+	}
+	return traceContext{TraceID: newUUID()}
+}